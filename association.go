@@ -2,6 +2,7 @@ package rel
 
 import (
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 
@@ -20,6 +21,9 @@ const (
 	HasMany
 	// ManyToMany association.
 	ManyToMany
+	// Polymorphic association, a HasOne/HasMany association resolved through
+	// a type+id discriminator pair instead of a plain foreign key.
+	Polymorphic
 )
 
 type associationKey struct {
@@ -28,16 +32,23 @@ type associationKey struct {
 }
 
 type associationData struct {
-	typ              AssociationType
-	targetIndex      []int
-	referenceField   string
-	referenceIndex   int
-	referenceThrough string
-	foreignField     string
-	foreignIndex     int
-	foreignThrough   string
-	through          string
-	autosave         bool
+	typ                  AssociationType
+	targetIndex          []int
+	referenceFields      []string
+	referenceIndexes     []int
+	referenceThrough     string
+	foreignFields        []string
+	foreignIndexes       []int
+	foreignThrough       string
+	through              string
+	autosave             bool
+	polymorphic          bool
+	polymorphicType      string
+	polymorphicTypeField string
+	polymorphicTypeIndex int
+	polymorphicValue     string
+	onDelete             string
+	onUpdate             string
 }
 
 var associationCache sync.Map
@@ -114,9 +125,10 @@ func (a Association) IsZero() bool {
 	return isDeepZero(reflect.Indirect(rv), 1)
 }
 
-// ReferenceField of the association.
-func (a Association) ReferenceField() string {
-	return a.data.referenceField
+// ReferenceField of the association. More than one field means the
+// association is resolved by a composite key.
+func (a Association) ReferenceField() []string {
+	return a.data.referenceFields
 }
 
 // ReferenceThrough return intermediary foreign field used for many to many association.
@@ -124,14 +136,23 @@ func (a Association) ReferenceThrough() string {
 	return a.data.referenceThrough
 }
 
-// ReferenceValue of the association.
-func (a Association) ReferenceValue() interface{} {
-	return indirect(a.rv.Field(a.data.referenceIndex))
+// ReferenceValue of the association, ordered the same as ReferenceField.
+func (a Association) ReferenceValue() []interface{} {
+	var (
+		values = make([]interface{}, len(a.data.referenceIndexes))
+	)
+
+	for i, index := range a.data.referenceIndexes {
+		values[i] = indirect(a.rv.Field(index))
+	}
+
+	return values
 }
 
-// ForeignField of the association.
-func (a Association) ForeignField() string {
-	return a.data.foreignField
+// ForeignField of the association. More than one field means the
+// association is resolved by a composite key.
+func (a Association) ForeignField() []string {
+	return a.data.foreignFields
 }
 
 // ForeignThrough return intermediary foreign field used for many to many association.
@@ -139,22 +160,31 @@ func (a Association) ForeignThrough() string {
 	return a.data.foreignThrough
 }
 
-// ForeignValue of the association.
+// ForeignValue of the association, ordered the same as ForeignField.
 // It'll panic if association type is has many.
-func (a Association) ForeignValue() interface{} {
-	if a.Type() == HasMany || a.Type() == ManyToMany {
-		panic("cannot infer foreign value for has many or many to many association")
-	}
-
+func (a Association) ForeignValue() []interface{} {
 	var (
 		rv = a.rv.FieldByIndex(a.data.targetIndex)
 	)
 
+	if a.Type() == HasMany || a.Type() == ManyToMany ||
+		rv.Kind() == reflect.Slice || (rv.Kind() == reflect.Ptr && rv.Type().Elem().Kind() == reflect.Slice) {
+		panic("cannot infer foreign value for has many or many to many association")
+	}
+
 	if rv.Kind() == reflect.Ptr {
 		rv = rv.Elem()
 	}
 
-	return indirect(rv.Field(a.data.foreignIndex))
+	var (
+		values = make([]interface{}, len(a.data.foreignIndexes))
+	)
+
+	for i, index := range a.data.foreignIndexes {
+		values[i] = indirect(rv.Field(index))
+	}
+
+	return values
 }
 
 // Through return intermediary table used for many to many association.
@@ -167,6 +197,63 @@ func (a Association) Autosave() bool {
 	return a.data.autosave
 }
 
+// PolymorphicType returns the name declared in the `polymorphic` tag (e.g.
+// "Owner"), or an empty string if this association is not polymorphic.
+func (a Association) PolymorphicType() string {
+	return a.data.polymorphicType
+}
+
+// PolymorphicTypeField returns the column storing the polymorphic type
+// discriminator (e.g. "owner_type").
+func (a Association) PolymorphicTypeField() string {
+	return a.data.polymorphicTypeField
+}
+
+// PolymorphicValue returns the value to be stored in and matched against
+// PolymorphicTypeField (e.g. "posts").
+func (a Association) PolymorphicValue() string {
+	return a.data.polymorphicValue
+}
+
+// OnDelete returns the referential action (cascade, restrict, set_null or
+// no_action) declared for this association's foreign key, or an empty
+// string if none was declared.
+func (a Association) OnDelete() string {
+	return a.data.onDelete
+}
+
+// OnUpdate returns the referential action (cascade, restrict, set_null or
+// no_action) declared for this association's foreign key, or an empty
+// string if none was declared.
+func (a Association) OnUpdate() string {
+	return a.data.onUpdate
+}
+
+// ReferentialActionClause formats OnDelete/OnUpdate as the trailing clause
+// of a FOREIGN KEY definition (e.g. "ON DELETE CASCADE ON UPDATE
+// RESTRICT"), or an empty string if neither was declared. It only formats
+// the clause text; nothing in this package yet calls it to emit a `FOREIGN
+// KEY (...) REFERENCES ...` constraint during migrations, so wiring a
+// migration generator up to ReferenceField()/ForeignField()/Through() and
+// this method remains a follow-up.
+func (a Association) ReferentialActionClause() string {
+	var clauses []string
+
+	if a.data.onDelete != "" {
+		clauses = append(clauses, "ON DELETE "+referentialActionSQL(a.data.onDelete))
+	}
+
+	if a.data.onUpdate != "" {
+		clauses = append(clauses, "ON UPDATE "+referentialActionSQL(a.data.onUpdate))
+	}
+
+	return strings.Join(clauses, " ")
+}
+
+func referentialActionSQL(action string) string {
+	return strings.ToUpper(strings.ReplaceAll(action, "_", " "))
+}
+
 func newAssociation(rv reflect.Value, index int) Association {
 	if rv.Kind() == reflect.Ptr {
 		rv = rv.Elem()
@@ -191,61 +278,147 @@ func extractAssociationData(rt reflect.Type, index int) associationData {
 	}
 
 	var (
-		sf              = rt.Field(index)
-		ft              = sf.Type
-		ref, refThrough = getAssocField(sf.Tag, "ref")
-		fk, fkThrough   = getAssocField(sf.Tag, "fk")
-		through         = sf.Tag.Get("through")
-		fName           = fieldName(sf)
-		assocData       = associationData{
+		sf                     = rt.Field(index)
+		ft                     = sf.Type
+		ref, refThrough        = getAssocField(sf.Tag, "ref")
+		fk, fkThrough          = getAssocField(sf.Tag, "fk")
+		throughTag, hasThrough = sf.Tag.Lookup("through")
+		polymorphic            = sf.Tag.Get("polymorphic")
+		onDelete               = sf.Tag.Get("on_delete")
+		onUpdate               = sf.Tag.Get("on_update")
+		fName                  = fieldName(sf)
+		assocData              = associationData{
 			targetIndex: sf.Index,
 			autosave:    sf.Tag.Get("autosave") == "true",
+			onDelete:    onDelete,
+			onUpdate:    onUpdate,
 		}
 	)
 
+	if onDelete != "" && !isValidReferentialAction(onDelete) {
+		panic("rel: invalid on_delete action (" + onDelete + ")")
+	}
+
+	if onUpdate != "" && !isValidReferentialAction(onUpdate) {
+		panic("rel: invalid on_update action (" + onUpdate + ")")
+	}
+
 	for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice {
 		ft = ft.Elem()
 	}
 
+	var (
+		through string
+	)
+
+	if hasThrough {
+		if throughTag == "" || throughTag == "auto" {
+			through = joinTableName(rt, ft)
+		} else {
+			through = throughTag
+		}
+	}
+
 	var (
 		refDocData = extractDocumentData(rt, true)
 		fkDocData  = extractDocumentData(ft, true)
 	)
 
+	var (
+		polymorphicTypeField string
+		polymorphicValue     string
+	)
+
+	if polymorphic != "" {
+		polymorphicTypeField = sf.Tag.Get("polymorphic_type")
+		if polymorphicTypeField == "" {
+			polymorphicTypeField = snaker.CamelToSnake(polymorphic) + "_type"
+		}
+
+		polymorphicValue = sf.Tag.Get("polymorphic_value")
+		if polymorphicValue == "" {
+			polymorphicValue = snaker.CamelToSnake(rt.Name())
+		}
+	}
+
 	// Try to guess ref and fk if not defined.
-	if ref == "" || fk == "" {
+	if len(ref) == 0 || len(fk) == 0 {
 		// TODO: replace "id" with inferred primary field
-		if through != "" {
-			ref = "id"
-			fk = "id"
+		if polymorphic != "" {
+			ref = []string{"id"}
+			polymorphicID := sf.Tag.Get("polymorphic_id")
+			if polymorphicID == "" {
+				polymorphicID = snaker.CamelToSnake(polymorphic) + "_id"
+			}
+			fk = []string{polymorphicID}
+		} else if through != "" {
+			ref = []string{"id"}
+			fk = []string{"id"}
 			refThrough = snaker.CamelToSnake(rt.Name()) + "_id"
 			fkThrough = snaker.CamelToSnake(ft.Name()) + "_id"
 		} else if _, isBelongsTo := refDocData.index[fName+"_id"]; isBelongsTo {
-			ref = fName + "_id"
-			fk = "id"
+			ref = []string{fName + "_id"}
+			fk = []string{"id"}
 		} else {
-			ref = "id"
-			fk = snaker.CamelToSnake(rt.Name()) + "_id"
+			ref = []string{"id"}
+			fk = []string{snaker.CamelToSnake(rt.Name()) + "_id"}
 		}
 	}
 
-	if id, exist := refDocData.index[ref]; !exist {
-		panic("rel: references (" + ref + ") field not found ")
-	} else {
-		assocData.referenceIndex = id
-		assocData.referenceField = ref
+	if len(ref) != len(fk) {
+		panic("rel: references and foreign_key must have the same number of fields")
 	}
 
-	if id, exist := fkDocData.index[fk]; !exist {
-		panic("rel: foreign_key (" + fk + ") field not found")
-	} else {
-		assocData.foreignIndex = id
-		assocData.foreignField = fk
+	// A single-field fk of "id" means the foreign side is referenced by its
+	// own primary key, which only happens for BelongsTo - HasOne/HasMany
+	// always point the other way (fk is the child's "<parent>_id" column).
+	// This covers both the guessed case above and an explicit single-field
+	// ref/fk tag pair, so pre-existing BelongsTo tags don't need to be
+	// migrated to belongs_to:"true". Composite keys can't be inferred this
+	// way, since neither side is reliably "id", so those still require an
+	// explicit belongs_to:"true" tag to disambiguate from HasOne.
+	var (
+		singleFieldBelongsTo = len(fk) == 1 && fk[0] == "id"
+	)
+
+	assocData.referenceFields = ref
+	assocData.referenceIndexes = make([]int, len(ref))
+	for i, name := range ref {
+		if id, exist := lookupField(rt, refDocData, name); !exist {
+			panic("rel: references (" + name + ") field not found ")
+		} else {
+			assocData.referenceIndexes[i] = id
+		}
+	}
+
+	assocData.foreignFields = fk
+	assocData.foreignIndexes = make([]int, len(fk))
+	for i, name := range fk {
+		if id, exist := lookupField(ft, fkDocData, name); !exist {
+			panic("rel: foreign_key (" + name + ") field not found")
+		} else {
+			assocData.foreignIndexes[i] = id
+		}
+	}
+
+	if polymorphic != "" {
+		if id, exist := lookupField(ft, fkDocData, polymorphicTypeField); !exist {
+			panic("rel: polymorphic type (" + polymorphicTypeField + ") field not found")
+		} else {
+			assocData.polymorphicTypeIndex = id
+		}
+
+		assocData.polymorphic = true
+		assocData.polymorphicType = polymorphic
+		assocData.polymorphicTypeField = polymorphicTypeField
+		assocData.polymorphicValue = polymorphicValue
 	}
 
 	// guess assoc type
 	if sf.Type.Kind() == reflect.Slice || (sf.Type.Kind() == reflect.Ptr && sf.Type.Elem().Kind() == reflect.Slice) {
-		if through != "" {
+		if polymorphic != "" {
+			assocData.typ = Polymorphic
+		} else if through != "" {
 			assocData.typ = ManyToMany
 			assocData.referenceThrough = refThrough
 			assocData.foreignThrough = fkThrough
@@ -254,23 +427,100 @@ func extractAssociationData(rt reflect.Type, index int) associationData {
 			assocData.typ = HasMany
 		}
 	} else {
-		if len(assocData.referenceField) > len(assocData.foreignField) {
+		if polymorphic != "" {
+			assocData.typ = Polymorphic
+		} else if singleFieldBelongsTo || sf.Tag.Get("belongs_to") == "true" {
 			assocData.typ = BelongsTo
 		} else {
 			assocData.typ = HasOne
 		}
 	}
 
+	// Many to many has no FK column to nullify: the relationship lives
+	// entirely in the join row, which is deleted outright instead of
+	// updated, so set_null doesn't apply. Without this, the check below
+	// would inspect foreignIndexes resolved against ft's own primary key
+	// (the join's "id" column isn't a foreign key at all) and reject
+	// perfectly valid many to many associations.
+	if assocData.onDelete == "set_null" && assocData.typ != ManyToMany {
+		var (
+			fkType    = ft
+			fkIndexes = assocData.foreignIndexes
+		)
+
+		if assocData.typ == BelongsTo {
+			fkType = rt
+			fkIndexes = assocData.referenceIndexes
+		}
+
+		for _, idx := range fkIndexes {
+			if fkType.Field(idx).Type.Kind() != reflect.Ptr {
+				panic("rel: on_delete=set_null requires a nullable foreign key field")
+			}
+		}
+	}
+
 	associationCache.Store(key, assocData)
 
 	return assocData
 }
 
-func getAssocField(tag reflect.StructTag, field string) (string, string) {
-	fields := strings.Split(tag.Get(field), ":")
-	if len(fields) == 2 {
-		return fields[0], fields[1]
+// joinTableName infers a many to many join table name from the two
+// participating struct names, in lexical order, using Inflector to
+// pluralize each (e.g. "User" + "Role" -> "roles_users").
+func joinTableName(a, b reflect.Type) string {
+	var (
+		names = []string{
+			Inflector.Pluralize(snaker.CamelToSnake(a.Name())),
+			Inflector.Pluralize(snaker.CamelToSnake(b.Name())),
+		}
+	)
+
+	sort.Strings(names)
+
+	return names[0] + "_" + names[1]
+}
+
+func isValidReferentialAction(action string) bool {
+	switch action {
+	case "cascade", "restrict", "set_null", "no_action":
+		return true
+	default:
+		return false
+	}
+}
+
+// lookupField resolves name against docData's Go-field index first, falling
+// back to a struct field whose `db` tag matches name. This lets ref/fk tags
+// (and the guesses derived from them) name either the Go-inferred column or
+// a column renamed with `db:"..."`.
+func lookupField(rt reflect.Type, docData documentData, name string) (int, bool) {
+	if id, exist := docData.index[name]; exist {
+		return id, true
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		if db, ok := rt.Field(i).Tag.Lookup("db"); ok && db == name {
+			return i, true
+		}
+	}
+
+	return 0, false
+}
+
+func getAssocField(tag reflect.StructTag, field string) ([]string, string) {
+	parts := strings.Split(tag.Get(field), ":")
+	if parts[0] == "" {
+		return nil, ""
+	}
+
+	var (
+		fields = strings.Split(parts[0], ",")
+	)
+
+	if len(parts) == 2 {
+		return fields, parts[1]
 	}
 
-	return fields[0], ""
+	return fields, ""
 }
@@ -0,0 +1,512 @@
+package rel
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/serenize/snaker"
+)
+
+// associationRepository is the narrow persistence surface AssociationMode
+// needs: run a block inside a transaction, insert/update a record, raw
+// insert/delete a many to many join row, and aggregate a count. Repository
+// (the interface real adapters implement) already has all of these, so
+// Associate takes associationRepository directly rather than declaring a
+// second, competing Repository type - any Repository satisfies it as-is.
+type associationRepository interface {
+	// Transaction runs fn with ctx scoped to a single database transaction,
+	// rolling back if fn returns a non-nil error.
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
+
+	// Insert persists record.
+	Insert(ctx context.Context, record interface{}) error
+
+	// Update persists changes already set on record.
+	Update(ctx context.Context, record interface{}) error
+
+	// InsertInto inserts values as a single row into table, used for many
+	// to many join rows that have no corresponding Go struct.
+	InsertInto(ctx context.Context, table string, values map[string]interface{}) error
+
+	// DeleteFrom removes rows from table matching where, used for many to
+	// many join rows.
+	DeleteFrom(ctx context.Context, table string, where map[string]interface{}) error
+
+	// Aggregate runs an aggregate query (e.g. "count") over table scoped by
+	// where and returns the result.
+	Aggregate(ctx context.Context, table string, aggregate string, field string, where map[string]interface{}) (int, error)
+}
+
+// AssociationMode provides a fluent interface to mutate the children of an
+// association directly, without loading the parent's association and
+// re-saving it under autosave semantics. All mutations run inside a single
+// transaction.
+type AssociationMode struct {
+	repo        associationRepository
+	association Association
+}
+
+// Associate builds the AssociationMode for record's field, scoped to repo.
+// It's the implementation behind a Repository's Association(record, field)
+// method, e.g.:
+//
+//	func (r *repository) Association(record interface{}, field string) *AssociationMode {
+//		return rel.Associate(r, record, field)
+//	}
+func Associate(repo associationRepository, record interface{}, field string) *AssociationMode {
+	var (
+		rv = reflect.ValueOf(record)
+	)
+
+	if rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+
+	sf, ok := rv.Type().FieldByName(field)
+	if !ok {
+		panic("rel: field (" + field + ") not found")
+	}
+
+	return newAssociationMode(repo, newAssociation(rv.Addr(), sf.Index[0]))
+}
+
+func newAssociationMode(repo associationRepository, association Association) *AssociationMode {
+	return &AssociationMode{
+		repo:        repo,
+		association: association,
+	}
+}
+
+// Append values as new children of the association.
+func (am *AssociationMode) Append(ctx context.Context, values ...interface{}) error {
+	return am.repo.Transaction(ctx, func(ctx context.Context) error {
+		var (
+			rv, isMany = am.target()
+		)
+
+		for _, value := range values {
+			if err := am.appendOne(ctx, rv, isMany, value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (am *AssociationMode) appendOne(ctx context.Context, rv reflect.Value, isMany bool, value interface{}) error {
+	var (
+		child = reflect.Indirect(reflect.ValueOf(value))
+	)
+
+	if err := am.insert(ctx, child); err != nil {
+		return err
+	}
+
+	if isMany {
+		rv.Set(reflect.Append(rv, am.elemValue(rv, child)))
+	} else {
+		am.setTarget(rv, child)
+	}
+
+	return nil
+}
+
+// Replace the association's children with values: children already present
+// (matched by primary key) are left alone, children no longer present are
+// detached, and values without a persisted primary key are inserted as new
+// children.
+func (am *AssociationMode) Replace(ctx context.Context, values ...interface{}) error {
+	return am.repo.Transaction(ctx, func(ctx context.Context) error {
+		var (
+			rv, isMany = am.target()
+		)
+
+		if !isMany {
+			var (
+				current, _ = am.primaryValue(reflect.Indirect(rv))
+			)
+
+			for _, value := range values {
+				if pk, persisted := am.primaryValue(reflect.Indirect(reflect.ValueOf(value))); persisted && pk == current {
+					return nil
+				}
+			}
+
+			if err := am.clear(ctx); err != nil {
+				return err
+			}
+
+			for _, value := range values {
+				if _, persisted := am.primaryValue(reflect.Indirect(reflect.ValueOf(value))); persisted {
+					continue
+				}
+
+				if err := am.appendOne(ctx, rv, isMany, value); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}
+
+		return am.replaceMany(ctx, rv, values)
+	})
+}
+
+func (am *AssociationMode) replaceMany(ctx context.Context, rv reflect.Value, values []interface{}) error {
+	var (
+		wanted = make(map[interface{}]bool, len(values))
+	)
+
+	for _, value := range values {
+		if pk, persisted := am.primaryValue(reflect.Indirect(reflect.ValueOf(value))); persisted {
+			wanted[pk] = true
+		}
+	}
+
+	var (
+		kept = reflect.MakeSlice(rv.Type(), 0, rv.Len())
+	)
+
+	for i := 0; i < rv.Len(); i++ {
+		var (
+			existing = reflect.Indirect(rv.Index(i))
+		)
+
+		if pk, persisted := am.primaryValue(existing); persisted && wanted[pk] {
+			kept = reflect.Append(kept, rv.Index(i))
+			continue
+		}
+
+		if err := am.detach(ctx, existing); err != nil {
+			return err
+		}
+	}
+
+	for _, value := range values {
+		var (
+			child = reflect.Indirect(reflect.ValueOf(value))
+		)
+
+		if _, persisted := am.primaryValue(child); persisted {
+			// Already has a primary key: either just kept above, or a
+			// pre-existing record the caller expects to already be
+			// attached. Neither case calls for a fresh Insert.
+			continue
+		}
+
+		if err := am.insert(ctx, child); err != nil {
+			return err
+		}
+
+		kept = reflect.Append(kept, am.elemValue(kept, child))
+	}
+
+	rv.Set(kept)
+
+	return nil
+}
+
+// Delete removes values from the association's children: for has one, has
+// many and polymorphic associations, the child's foreign key is nullified;
+// for many to many, only the join row is removed.
+func (am *AssociationMode) Delete(ctx context.Context, values ...interface{}) error {
+	return am.repo.Transaction(ctx, func(ctx context.Context) error {
+		var (
+			rv, isMany = am.target()
+		)
+
+		for _, value := range values {
+			var (
+				child = reflect.Indirect(reflect.ValueOf(value))
+				// detach mutates child's FK (and polymorphic type) fields
+				// in place, so the identity used to find it in rv has to
+				// be captured before that happens - matching the mutated
+				// child against the untouched slice/field would otherwise
+				// never succeed unless value happens to alias the exact
+				// same memory rv already holds.
+				before = reflect.New(child.Type()).Elem()
+			)
+
+			before.Set(child)
+
+			if err := am.detach(ctx, child); err != nil {
+				return err
+			}
+
+			if isMany {
+				am.removeFromSlice(rv, before)
+			} else if am.matches(reflect.Indirect(rv), before) {
+				rv.Set(reflect.Zero(rv.Type()))
+			}
+		}
+
+		return nil
+	})
+}
+
+// Clear removes all children of the association.
+func (am *AssociationMode) Clear(ctx context.Context) error {
+	return am.repo.Transaction(ctx, am.clear)
+}
+
+// Count runs SELECT COUNT(*) scoped by the parent key, rather than relying
+// on whatever children happen to already be loaded in memory.
+func (am *AssociationMode) Count(ctx context.Context) int {
+	var (
+		table string
+		where map[string]interface{}
+	)
+
+	if am.association.Type() == ManyToMany {
+		table = am.association.Through()
+		where = map[string]interface{}{
+			am.association.ReferenceThrough(): am.association.ReferenceValue()[0],
+		}
+	} else {
+		table = am.childTable()
+		where = am.parentWhere()
+	}
+
+	count, err := am.repo.Aggregate(ctx, table, "count", "*", where)
+	if err != nil {
+		return 0
+	}
+
+	return count
+}
+
+func (am *AssociationMode) clear(ctx context.Context) error {
+	var (
+		rv, isMany = am.target()
+	)
+
+	if isMany {
+		for i := 0; i < rv.Len(); i++ {
+			if err := am.detach(ctx, reflect.Indirect(rv.Index(i))); err != nil {
+				return err
+			}
+		}
+
+		rv.Set(reflect.MakeSlice(rv.Type(), 0, 0))
+
+		return nil
+	}
+
+	// target() has already allocated a zero child for a nil pointer, so a
+	// child without a primary key means there is no stored row to detach.
+	if _, persisted := am.primaryValue(reflect.Indirect(rv)); !persisted {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	if err := am.detach(ctx, reflect.Indirect(rv)); err != nil {
+		return err
+	}
+
+	rv.Set(reflect.Zero(rv.Type()))
+
+	return nil
+}
+
+func (am *AssociationMode) insert(ctx context.Context, child reflect.Value) error {
+	switch am.association.Type() {
+	case HasOne, HasMany, Polymorphic:
+		am.assignKeys(child)
+		return am.repo.Insert(ctx, child.Addr().Interface())
+	case ManyToMany:
+		if err := am.repo.Insert(ctx, child.Addr().Interface()); err != nil {
+			return err
+		}
+
+		return am.repo.InsertInto(ctx, am.association.Through(), am.joinRow(child))
+	default:
+		return am.repo.Insert(ctx, child.Addr().Interface())
+	}
+}
+
+func (am *AssociationMode) detach(ctx context.Context, child reflect.Value) error {
+	switch am.association.Type() {
+	case HasOne, HasMany, Polymorphic:
+		am.nullifyKeys(child)
+		return am.repo.Update(ctx, child.Addr().Interface())
+	case ManyToMany:
+		return am.repo.DeleteFrom(ctx, am.association.Through(), am.joinRow(child))
+	default:
+		return am.repo.Update(ctx, child.Addr().Interface())
+	}
+}
+
+// target returns the raw association field and whether it's a to-many
+// (slice) association, allocating a zero value for nil pointers/slices.
+func (am *AssociationMode) target() (reflect.Value, bool) {
+	var (
+		rv = am.association.rv.FieldByIndex(am.association.data.targetIndex)
+	)
+
+	if rv.Kind() == reflect.Ptr && rv.Type().Elem().Kind() == reflect.Slice {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+
+		return rv.Elem(), true
+	}
+
+	if rv.Kind() == reflect.Slice {
+		return rv, true
+	}
+
+	if rv.Kind() == reflect.Ptr && rv.IsNil() {
+		rv.Set(reflect.New(rv.Type().Elem()))
+	}
+
+	return rv, false
+}
+
+// elemValue adapts child to rv's slice element type, which may hold either
+// structs or pointers to structs.
+func (am *AssociationMode) elemValue(rv reflect.Value, child reflect.Value) reflect.Value {
+	if rv.Type().Elem().Kind() == reflect.Ptr {
+		return child.Addr()
+	}
+
+	return child
+}
+
+func (am *AssociationMode) setTarget(rv reflect.Value, child reflect.Value) {
+	if rv.Kind() == reflect.Ptr {
+		var (
+			ptr = reflect.New(rv.Type().Elem())
+		)
+
+		ptr.Elem().Set(child)
+		rv.Set(ptr)
+
+		return
+	}
+
+	rv.Set(child)
+}
+
+func (am *AssociationMode) removeFromSlice(rv reflect.Value, child reflect.Value) {
+	var (
+		kept = reflect.MakeSlice(rv.Type(), 0, rv.Len())
+	)
+
+	for i := 0; i < rv.Len(); i++ {
+		if !am.matches(reflect.Indirect(rv.Index(i)), child) {
+			kept = reflect.Append(kept, rv.Index(i))
+		}
+	}
+
+	rv.Set(kept)
+}
+
+// matches reports whether a and b are the same child, preferring a primary
+// key comparison (so a freshly loaded copy with the same id still matches)
+// and falling back to a deep comparison for records that aren't persisted.
+func (am *AssociationMode) matches(a, b reflect.Value) bool {
+	if pkA, okA := am.primaryValue(a); okA {
+		if pkB, okB := am.primaryValue(b); okB {
+			return pkA == pkB
+		}
+	}
+
+	return reflect.DeepEqual(a.Interface(), b.Interface())
+}
+
+// primaryValue returns rv's "id" field value and whether it's set. This
+// mirrors the same "id" assumption extractAssociationData's guessing makes
+// elsewhere (see its TODO about inferring the primary field).
+func (am *AssociationMode) primaryValue(rv reflect.Value) (interface{}, bool) {
+	var (
+		docData   = extractDocumentData(rv.Type(), true)
+		id, exist = lookupField(rv.Type(), docData, "id")
+	)
+
+	if !exist {
+		return nil, false
+	}
+
+	var (
+		field = rv.Field(id)
+	)
+
+	if isDeepZero(field, 1) {
+		return nil, false
+	}
+
+	return indirect(field), true
+}
+
+func (am *AssociationMode) assignKeys(child reflect.Value) {
+	var (
+		data   = am.association.data
+		values = am.association.ReferenceValue()
+	)
+
+	for i, index := range data.foreignIndexes {
+		child.Field(index).Set(reflect.ValueOf(values[i]))
+	}
+
+	if data.polymorphic {
+		child.Field(data.polymorphicTypeIndex).SetString(data.polymorphicValue)
+	}
+}
+
+func (am *AssociationMode) nullifyKeys(child reflect.Value) {
+	var (
+		data = am.association.data
+	)
+
+	for _, index := range data.foreignIndexes {
+		child.Field(index).Set(reflect.Zero(child.Field(index).Type()))
+	}
+
+	if data.polymorphic {
+		child.Field(data.polymorphicTypeIndex).SetString("")
+	}
+}
+
+// joinRow builds the many to many join row tying the parent key to child's
+// foreign key column.
+func (am *AssociationMode) joinRow(child reflect.Value) map[string]interface{} {
+	return map[string]interface{}{
+		am.association.ReferenceThrough(): am.association.ReferenceValue()[0],
+		am.association.ForeignThrough():   indirect(child.Field(am.association.data.foreignIndexes[0])),
+	}
+}
+
+// childTable infers the child struct's table name (pluralized, snake-cased)
+// for has one, has many and polymorphic Count queries.
+func (am *AssociationMode) childTable() string {
+	var (
+		ft = am.association.rv.FieldByIndex(am.association.data.targetIndex).Type()
+	)
+
+	for ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Slice {
+		ft = ft.Elem()
+	}
+
+	return Inflector.Pluralize(snaker.CamelToSnake(ft.Name()))
+}
+
+// parentWhere scopes a has one, has many or polymorphic Count query to the
+// parent's key (and, for polymorphic associations, its type).
+func (am *AssociationMode) parentWhere() map[string]interface{} {
+	var (
+		refValues = am.association.ReferenceValue()
+		where     = make(map[string]interface{}, len(refValues)+1)
+	)
+
+	for i, field := range am.association.ForeignField() {
+		where[field] = refValues[i]
+	}
+
+	if am.association.data.polymorphic {
+		where[am.association.PolymorphicTypeField()] = am.association.PolymorphicValue()
+	}
+
+	return where
+}
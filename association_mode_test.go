@@ -0,0 +1,255 @@
+package rel
+
+import (
+	"context"
+	"testing"
+)
+
+type fakeAssocRepo struct {
+	inserted  []interface{}
+	updated   []interface{}
+	joinIns   []map[string]interface{}
+	joinDel   []map[string]interface{}
+	aggResult int
+}
+
+func (r *fakeAssocRepo) Transaction(ctx context.Context, fn func(context.Context) error) error {
+	return fn(ctx)
+}
+
+func (r *fakeAssocRepo) Insert(ctx context.Context, record interface{}) error {
+	r.inserted = append(r.inserted, record)
+	return nil
+}
+
+func (r *fakeAssocRepo) Update(ctx context.Context, record interface{}) error {
+	r.updated = append(r.updated, record)
+	return nil
+}
+
+func (r *fakeAssocRepo) InsertInto(ctx context.Context, table string, values map[string]interface{}) error {
+	r.joinIns = append(r.joinIns, values)
+	return nil
+}
+
+func (r *fakeAssocRepo) DeleteFrom(ctx context.Context, table string, where map[string]interface{}) error {
+	r.joinDel = append(r.joinDel, where)
+	return nil
+}
+
+func (r *fakeAssocRepo) Aggregate(ctx context.Context, table, aggregate, field string, where map[string]interface{}) (int, error) {
+	return r.aggResult, nil
+}
+
+type modeAddress struct {
+	Id         int
+	ModeUserId int
+	City       string
+}
+
+type modeProfile struct {
+	Id         int
+	ModeUserId int
+	Bio        string
+}
+
+type modeRole struct {
+	Id   int
+	Name string
+}
+
+type modeUser struct {
+	Id      int
+	Profile *modeProfile
+	Address []modeAddress
+	Roles   []modeRole `through:""`
+}
+
+func TestAssociationMode_AppendHasMany(t *testing.T) {
+	var (
+		u    = &modeUser{Id: 1}
+		repo = &fakeAssocRepo{}
+		am   = Associate(repo, u, "Address")
+	)
+
+	if err := am.Append(context.Background(), &modeAddress{City: "NYC"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(u.Address) != 1 || u.Address[0].ModeUserId != 1 {
+		t.Fatalf("got %+v", u.Address)
+	}
+
+	if len(repo.inserted) != 1 {
+		t.Fatalf("expected 1 insert, got %d", len(repo.inserted))
+	}
+}
+
+func TestAssociationMode_AppendHasOne(t *testing.T) {
+	var (
+		u    = &modeUser{Id: 1}
+		repo = &fakeAssocRepo{}
+		am   = Associate(repo, u, "Profile")
+	)
+
+	if err := am.Append(context.Background(), &modeProfile{Bio: "hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Profile == nil || u.Profile.ModeUserId != 1 {
+		t.Fatalf("got %+v", u.Profile)
+	}
+}
+
+func TestAssociationMode_DeleteHasOne(t *testing.T) {
+	var (
+		u    = &modeUser{Id: 1, Profile: &modeProfile{Id: 5, ModeUserId: 1, Bio: "hi"}}
+		repo = &fakeAssocRepo{}
+		am   = Associate(repo, u, "Profile")
+	)
+
+	if err := am.Delete(context.Background(), u.Profile); err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Profile != nil {
+		t.Fatalf("expected profile detached, got %+v", u.Profile)
+	}
+
+	if len(repo.updated) != 1 {
+		t.Fatalf("expected 1 update, got %d", len(repo.updated))
+	}
+
+	if repo.updated[0].(*modeProfile).ModeUserId != 0 {
+		t.Fatalf("expected FK nullified on the persisted copy, got %+v", repo.updated[0])
+	}
+}
+
+func TestAssociationMode_DeleteHasOneByPrimaryKey(t *testing.T) {
+	var (
+		u    = &modeUser{Id: 1, Profile: &modeProfile{Id: 5, ModeUserId: 1, Bio: "hi"}}
+		repo = &fakeAssocRepo{}
+		am   = Associate(repo, u, "Profile")
+		// Independently constructed, not the same pointer already held by
+		// u.Profile, but it matches the same primary key.
+		copyOfProfile = &modeProfile{Id: 5, ModeUserId: 1, Bio: "hi"}
+	)
+
+	if err := am.Delete(context.Background(), copyOfProfile); err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Profile != nil {
+		t.Fatalf("expected profile detached in memory, got %+v", u.Profile)
+	}
+
+	if len(repo.updated) != 1 {
+		t.Fatalf("expected 1 update, got %d", len(repo.updated))
+	}
+}
+
+func TestAssociationMode_ManyToMany(t *testing.T) {
+	var (
+		u    = &modeUser{Id: 7}
+		repo = &fakeAssocRepo{}
+		am   = Associate(repo, u, "Roles")
+		role = &modeRole{Id: 42, Name: "admin"}
+	)
+
+	if err := am.Append(context.Background(), role); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.joinIns) != 1 {
+		t.Fatalf("expected 1 join insert, got %d", len(repo.joinIns))
+	}
+
+	if repo.joinIns[0]["mode_user_id"] != 7 {
+		t.Fatalf("got %+v", repo.joinIns[0])
+	}
+
+	if err := am.Delete(context.Background(), role); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.joinDel) != 1 {
+		t.Fatalf("expected 1 join delete, got %d", len(repo.joinDel))
+	}
+}
+
+func TestAssociationMode_Count(t *testing.T) {
+	var (
+		u    = &modeUser{Id: 3}
+		repo = &fakeAssocRepo{aggResult: 9}
+		am   = Associate(repo, u, "Address")
+	)
+
+	if n := am.Count(context.Background()); n != 9 {
+		t.Fatalf("expected 9, got %d", n)
+	}
+}
+
+func TestAssociationMode_Clear(t *testing.T) {
+	var (
+		u = &modeUser{
+			Id: 1,
+			Address: []modeAddress{
+				{Id: 1, ModeUserId: 1, City: "A"},
+				{Id: 2, ModeUserId: 1, City: "B"},
+			},
+		}
+		repo = &fakeAssocRepo{}
+		am   = Associate(repo, u, "Address")
+	)
+
+	if err := am.Clear(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(u.Address) != 0 {
+		t.Fatalf("expected cleared, got %+v", u.Address)
+	}
+
+	if len(repo.updated) != 2 {
+		t.Fatalf("expected 2 updates (nullify), got %d", len(repo.updated))
+	}
+}
+
+func TestAssociationMode_Replace(t *testing.T) {
+	var (
+		u = &modeUser{
+			Id: 1,
+			Address: []modeAddress{
+				{Id: 1, ModeUserId: 1, City: "A"},
+				{Id: 2, ModeUserId: 1, City: "B"},
+			},
+		}
+		repo = &fakeAssocRepo{}
+		am   = Associate(repo, u, "Address")
+
+		// Keep address 1 (already persisted), drop address 2, add a new
+		// unpersisted address.
+		kept = &modeAddress{Id: 1, ModeUserId: 1, City: "A"}
+		new  = &modeAddress{City: "C"}
+	)
+
+	if err := am.Replace(context.Background(), kept, new); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(repo.inserted) != 1 {
+		t.Fatalf("expected only the new address to be inserted, got %d inserts: %+v", len(repo.inserted), repo.inserted)
+	}
+
+	if len(repo.updated) != 1 {
+		t.Fatalf("expected address 2 to be detached via 1 update, got %d: %+v", len(repo.updated), repo.updated)
+	}
+
+	if repo.updated[0].(*modeAddress).Id != 2 {
+		t.Fatalf("expected address 2 to be the one detached, got %+v", repo.updated[0])
+	}
+
+	if len(u.Address) != 2 {
+		t.Fatalf("expected 2 addresses after replace, got %+v", u.Address)
+	}
+}
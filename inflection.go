@@ -0,0 +1,84 @@
+package rel
+
+import "strings"
+
+// Inflector pluralizes and singularizes English words. It's used to infer
+// many to many join table names (e.g. "User" + "Role" -> "roles_users")
+// when a through tag doesn't name one explicitly. It's a package-level
+// variable so callers can swap in their own rules for domain-specific
+// vocabulary.
+var Inflector interface {
+	Pluralize(word string) string
+	Singularize(word string) string
+} = defaultInflector{}
+
+type defaultInflector struct{}
+
+var irregularPlurals = map[string]string{
+	"person": "people",
+	"child":  "children",
+	"mouse":  "mice",
+	"man":    "men",
+	"woman":  "women",
+	"tooth":  "teeth",
+	"foot":   "feet",
+	"goose":  "geese",
+}
+
+var irregularSingulars = invert(irregularPlurals)
+
+func invert(m map[string]string) map[string]string {
+	inverted := make(map[string]string, len(m))
+	for k, v := range m {
+		inverted[v] = k
+	}
+
+	return inverted
+}
+
+// Pluralize returns the English plural form of word.
+func (defaultInflector) Pluralize(word string) string {
+	if plural, ok := irregularPlurals[word]; ok {
+		return plural
+	}
+
+	switch {
+	case strings.HasSuffix(word, "y") && len(word) > 1 && !isVowel(word[len(word)-2]):
+		return word[:len(word)-1] + "ies"
+	case strings.HasSuffix(word, "s"), strings.HasSuffix(word, "x"),
+		strings.HasSuffix(word, "ch"), strings.HasSuffix(word, "sh"):
+		return word + "es"
+	default:
+		return word + "s"
+	}
+}
+
+// Singularize returns the English singular form of word, using the
+// irregular-word table and then stripping a trailing "ies" (to "y"), "es"
+// or "s". Plurals whose singular ends in "e" lose that "e" too (e.g.
+// "roles" -> "rol").
+func (defaultInflector) Singularize(word string) string {
+	if singular, ok := irregularSingulars[word]; ok {
+		return singular
+	}
+
+	switch {
+	case strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "es"):
+		return word[:len(word)-2]
+	case strings.HasSuffix(word, "s"):
+		return word[:len(word)-1]
+	default:
+		return word
+	}
+}
+
+func isVowel(b byte) bool {
+	switch b {
+	case 'a', 'e', 'i', 'o', 'u':
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,56 @@
+package rel
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPluralize(t *testing.T) {
+	tests := map[string]string{
+		"user":   "users",
+		"role":   "roles",
+		"box":    "boxes",
+		"city":   "cities",
+		"day":    "days",
+		"bus":    "buses",
+		"church": "churches",
+		"dish":   "dishes",
+		"person": "people",
+		"child":  "children",
+		"mouse":  "mice",
+	}
+
+	for word, want := range tests {
+		if got := Inflector.Pluralize(word); got != want {
+			t.Errorf("Pluralize(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestSingularize(t *testing.T) {
+	tests := map[string]string{
+		"users":    "user",
+		"boxes":    "box",
+		"cities":   "city",
+		"people":   "person",
+		"children": "child",
+		"mice":     "mouse",
+	}
+
+	for word, want := range tests {
+		if got := Inflector.Singularize(word); got != want {
+			t.Errorf("Singularize(%q) = %q, want %q", word, got, want)
+		}
+	}
+}
+
+func TestJoinTableName(t *testing.T) {
+	var (
+		userType = reflect.TypeOf(modeUser{})
+		roleType = reflect.TypeOf(modeRole{})
+	)
+
+	if got, want := joinTableName(userType, roleType), "mode_roles_mode_users"; got != want {
+		t.Fatalf("joinTableName() = %q, want %q", got, want)
+	}
+}